@@ -0,0 +1,151 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/avahowell/spid/sentinel"
+
+	"github.com/howeyc/gopass"
+	"golang.org/x/sys/unix"
+)
+
+// daemonChildEnvVar marks a re-exec'd spid process as the backgrounded
+// daemon child rather than a fresh invocation.
+const daemonChildEnvVar = "SPID_DAEMON_CHILD"
+
+// daemonPPIDEnvVar carries the parent's pid to the child so it can signal
+// readiness back with SIGUSR1.
+const daemonPPIDEnvVar = "SPID_DAEMON_PPID"
+
+// daemonCmd implements `spid daemon`. It models gocryptfs' forkChild
+// pattern: the parent reads the password interactively, forks a detached
+// child that inherits the password itself (not a derived key) over a pipe
+// and derives the key on its own, waits for the child to
+// signal readiness with SIGUSR1 (or exit with an error), then exits itself
+// so the daemon keeps running after the terminal is closed.
+func daemonCmd(configPath, dbPath string, fix bool, keyfiles []string, pidfile string) error {
+	if os.Getenv(daemonChildEnvVar) == "1" {
+		return runDaemonChild(configPath, dbPath, fix, keyfiles, pidfile)
+	}
+
+	fmt.Printf("Password for %v: ", dbPath)
+	pass, err := gopass.GetPasswd()
+	if err != nil {
+		return err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	ready := make(chan os.Signal, 1)
+	signal.Notify(ready, syscall.SIGUSR1)
+	defer signal.Stop(ready)
+
+	child, err := os.StartProcess(os.Args[0], os.Args, &os.ProcAttr{
+		Env: append(os.Environ(),
+			daemonChildEnvVar+"=1",
+			daemonPPIDEnvVar+"="+strconv.Itoa(os.Getpid())),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr, r},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append([]byte(pass), '\n')); err != nil {
+		return err
+	}
+	w.Close()
+	r.Close()
+
+	exited := make(chan *os.ProcessState, 1)
+	go func() {
+		st, _ := child.Wait()
+		exited <- st
+	}()
+
+	select {
+	case <-ready:
+		fmt.Printf("spid daemon started, pid %d\n", child.Pid)
+		return nil
+	case st := <-exited:
+		return fmt.Errorf("daemon child exited before signalling readiness: %v", st)
+	case <-time.After(30 * time.Second):
+		child.Kill()
+		return fmt.Errorf("daemon child did not signal readiness within 30s")
+	}
+}
+
+// runDaemonChild is the body of the backgrounded child: it reads the
+// password relayed over fd 3, asks to be killed if the parent dies before
+// it's ready, then runs the sentinel daemon loop until SIGTERM.
+func runDaemonChild(configPath, dbPath string, fix bool, keyfiles []string, pidfile string) error {
+	unix.Prctl(unix.PR_SET_PDEATHSIG, uintptr(syscall.SIGTERM), 0, 0, 0)
+
+	line, err := bufio.NewReader(os.NewFile(3, "daemon-pass")).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	password := strings.TrimSuffix(line, "\n")
+
+	s, err := sentinel.Open(dbPath, password, fix, keyfiles)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(configPath)
+	if err != nil {
+		return err
+	}
+	var config sentinel.Config
+	err = json.NewDecoder(f).Decode(&config)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	alerters, err := sentinel.NewAlerters(config.Alerters)
+	if err != nil {
+		return err
+	}
+
+	if pidfile != "" {
+		if err := os.WriteFile(pidfile, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+			return err
+		}
+		defer os.Remove(pidfile)
+	}
+
+	interval := time.Duration(config.ScanInterval)
+	if interval == 0 {
+		interval = time.Minute
+	}
+	daemon := sentinel.NewDaemon(s, dbPath, password, keyfiles, interval, alerters)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-term
+		cancel()
+	}()
+
+	// Ready: the parent is about to exit, so stop asking to die with it.
+	unix.Prctl(unix.PR_SET_PDEATHSIG, 0, 0, 0, 0)
+	if ppid, err := strconv.Atoi(os.Getenv(daemonPPIDEnvVar)); err == nil {
+		syscall.Kill(ppid, syscall.SIGUSR1)
+	}
+
+	return daemon.Run(ctx)
+}
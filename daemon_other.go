@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// daemonCmd is unsupported outside Linux: the daemon relies on PR_SET_PDEATHSIG
+// and SIGUSR1-based readiness signalling, both Linux-specific.
+func daemonCmd(configPath, dbPath string, fix bool, keyfiles []string, pidfile string) error {
+	return fmt.Errorf("spid daemon is only supported on linux")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/avahowell/spid/sentinel"
@@ -17,16 +18,31 @@ const usage = `
 
 spid: simple portable intrusion detection
 
-usage: spid [-db dbpath] [-config configpath] command
+usage: spid [-db dbpath] [-config configpath] [-fix] [-keyfile path]... [-ordered-keyfiles] [-pidfile path] command
 
 commands:
 
-init - initialize a new database using the supplied config and writing to the supplied dbpath
-scan - use the supplied database and run a scan, displaying any new events
+init   - initialize a new database using the supplied config and writing to the supplied dbpath
+scan   - use the supplied database and run a scan, displaying any new events
+daemon - keep the supplied database unlocked in memory and scan it on the config's ScanInterval,
+         dispatching events to its configured alerters, until terminated
 
 `
 
-func initCmd(configPath string, dbPath string) error {
+// keyfileList implements flag.Value so -keyfile can be passed more than once
+// to require more than one keyfile.
+type keyfileList []string
+
+func (k *keyfileList) String() string {
+	return strings.Join(*k, ",")
+}
+
+func (k *keyfileList) Set(path string) error {
+	*k = append(*k, path)
+	return nil
+}
+
+func initCmd(configPath string, dbPath string, keyfiles []string, ordered bool) error {
 	f, err := os.Open(configPath)
 	if err != nil {
 		return err
@@ -62,7 +78,9 @@ func initCmd(configPath string, dbPath string) error {
 	if string(pass) != string(pass2) {
 		return err
 	}
-	err = s.Save(dbPath, string(pass))
+	keyfiles = append(append([]string{}, config.Keyfiles...), keyfiles...)
+	s.KeyfilesOrdered = ordered || config.KeyfilesOrdered
+	err = s.Save(dbPath, string(pass), keyfiles)
 	if err != nil {
 		return err
 	}
@@ -70,13 +88,16 @@ func initCmd(configPath string, dbPath string) error {
 	return nil
 }
 
-func scanCmd(dbPath string) error {
+// scanCmd reuses the keyfile combination mode the database was actually
+// created with (reported by Open), ignoring -ordered-keyfiles entirely on
+// resave so it can't silently switch a database to the wrong mode.
+func scanCmd(dbPath string, fix bool, keyfiles []string) error {
 	fmt.Printf("Password for %v: ", dbPath)
 	pass, err := gopass.GetPasswd()
 	if err != nil {
 		return err
 	}
-	s, err := sentinel.Open(dbPath, string(pass))
+	s, err := sentinel.Open(dbPath, string(pass), fix, keyfiles)
 	if err != nil {
 		return err
 	}
@@ -101,7 +122,7 @@ func scanCmd(dbPath string) error {
 			fmt.Printf("    [%v %v] %v -> %v\n", ev.Evtype, ev.File, ev.OrigChecksum, ev.NewChecksum)
 		}
 	}
-	err = s.Save(dbPath, string(pass))
+	err = s.Save(dbPath, string(pass), keyfiles)
 	if err != nil {
 		return err
 	}
@@ -111,6 +132,11 @@ func scanCmd(dbPath string) error {
 func main() {
 	configPath := flag.String("config", "config.json", "path to the sentinel configuration")
 	dbPath := flag.String("db", "spid.db", "path to the spid atabase")
+	fix := flag.Bool("fix", false, "tolerate corrupted FEC chunks when opening the database, reporting only a count of repaired chunks")
+	var keyfiles keyfileList
+	flag.Var(&keyfiles, "keyfile", "path to a keyfile required alongside the password (may be repeated)")
+	orderedKeyfiles := flag.Bool("ordered-keyfiles", false, "require -keyfile paths to be supplied in the order used when the database was created")
+	pidfile := flag.String("pidfile", "", "write the daemon's pid to this path")
 	flag.Parse()
 
 	if len(flag.Args()) != 1 {
@@ -120,12 +146,17 @@ func main() {
 
 	cmd := flag.Args()[0]
 	if cmd == "init" {
-		err := initCmd(*configPath, *dbPath)
+		err := initCmd(*configPath, *dbPath, keyfiles, *orderedKeyfiles)
 		if err != nil {
 			log.Fatal(err)
 		}
 	} else if cmd == "scan" {
-		err := scanCmd(*dbPath)
+		err := scanCmd(*dbPath, *fix, keyfiles)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else if cmd == "daemon" {
+		err := daemonCmd(*configPath, *dbPath, *fix, keyfiles, *pidfile)
 		if err != nil {
 			log.Fatal(err)
 		}
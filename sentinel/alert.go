@@ -0,0 +1,163 @@
+package sentinel
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+)
+
+type (
+	// Alerter delivers a batch of Events produced by a Daemon scan to some
+	// external sink.
+	Alerter interface {
+		Alert(events []Event) error
+	}
+
+	// AlerterConfig describes one alert sink as loaded from a Daemon's JSON
+	// configuration. Which fields are required depends on Type.
+	AlerterConfig struct {
+		// Type selects the alerter implementation: "syslog", "exec",
+		// "smtp", or "webhook".
+		Type string
+
+		// Tag is the syslog tag used by the "syslog" alerter.
+		Tag string
+
+		// Command is the executable run by the "exec" alerter. The event
+		// batch is marshaled as JSON and written to its stdin.
+		Command string
+
+		// SMTPServer, SMTPFrom, and SMTPTo configure the "smtp" alerter.
+		SMTPServer string
+		SMTPFrom   string
+		SMTPTo     string
+
+		// WebhookURL and WebhookSecret configure the "webhook" alerter.
+		// Every request body is signed with HMAC-SHA256 over WebhookSecret
+		// and sent in the X-Spid-Signature header.
+		WebhookURL    string
+		WebhookSecret string
+	}
+)
+
+// NewAlerters builds the Alerter sinks described by cfgs.
+func NewAlerters(cfgs []AlerterConfig) ([]Alerter, error) {
+	alerters := make([]Alerter, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		a, err := newAlerter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		alerters = append(alerters, a)
+	}
+	return alerters, nil
+}
+
+func newAlerter(cfg AlerterConfig) (Alerter, error) {
+	switch cfg.Type {
+	case "syslog":
+		w, err := syslog.New(syslog.LOG_WARNING|syslog.LOG_DAEMON, cfg.Tag)
+		if err != nil {
+			return nil, err
+		}
+		return &syslogAlerter{w: w}, nil
+	case "exec":
+		return &execAlerter{command: cfg.Command}, nil
+	case "smtp":
+		return &smtpAlerter{server: cfg.SMTPServer, from: cfg.SMTPFrom, to: cfg.SMTPTo}, nil
+	case "webhook":
+		return &webhookAlerter{url: cfg.WebhookURL, secret: []byte(cfg.WebhookSecret), client: http.DefaultClient}, nil
+	default:
+		return nil, fmt.Errorf("sentinel: unknown alerter type %q", cfg.Type)
+	}
+}
+
+type syslogAlerter struct {
+	w *syslog.Writer
+}
+
+func (a *syslogAlerter) Alert(evs []Event) error {
+	for _, ev := range evs {
+		if err := a.w.Warning(fmt.Sprintf("[%s] %s", ev.Evtype, ev.File)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execAlerter runs Command once per batch, writing the events as JSON to
+// its stdin, matching the shell-hook pattern used by backup tools like
+// restic.
+type execAlerter struct {
+	command string
+}
+
+func (a *execAlerter) Alert(evs []Event) error {
+	payload, err := json.Marshal(evs)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(a.command)
+	cmd.Stdin = bytes.NewReader(payload)
+	return cmd.Run()
+}
+
+type smtpAlerter struct {
+	server string
+	from   string
+	to     string
+}
+
+func (a *smtpAlerter) Alert(evs []Event) error {
+	payload, err := json.MarshalIndent(evs, "", "  ")
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: spid: %d integrity event(s)\r\n\r\n%s",
+		a.from, a.to, len(evs), payload)
+	return smtp.SendMail(a.server, nil, a.from, []string{a.to}, []byte(msg))
+}
+
+// webhookAlerter posts each batch of events to a generic HTTPS endpoint,
+// signing the body so the receiver can verify it actually came from this
+// daemon.
+type webhookAlerter struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func (a *webhookAlerter) Alert(evs []Event) error {
+	payload, err := json.Marshal(evs)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write(payload)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequest(http.MethodPost, a.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Spid-Signature", sig)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %v returned status %v", a.url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package sentinel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExecAlerterWritesEventsAsJSON verifies that execAlerter runs its
+// command and pipes the event batch to its stdin as JSON.
+func TestExecAlerterWritesEventsAsJSON(t *testing.T) {
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "out.json")
+
+	script := filepath.Join(dir, "capture.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat > \""+outPath+"\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &execAlerter{command: script}
+	evs := []Event{{Evtype: evCreate, File: "/tmp/foo"}}
+	if err := a.Alert(evs); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Event
+	if err := json.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("stdin content was not the JSON event batch: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0].File != "/tmp/foo" || decoded[0].Evtype != evCreate {
+		t.Fatalf("unexpected decoded events: %+v", decoded)
+	}
+}
+
+// TestExecAlerterPropagatesCommandError verifies that a failing command
+// surfaces as an error rather than being swallowed.
+func TestExecAlerterPropagatesCommandError(t *testing.T) {
+	a := &execAlerter{command: "/nonexistent/spid-alert-hook"}
+	if err := a.Alert([]Event{{Evtype: evCreate, File: "/tmp/foo"}}); err == nil {
+		t.Fatal("expected an error running a nonexistent command")
+	}
+}
+
+// TestWebhookAlerterSignsAndPostsBody verifies that webhookAlerter POSTs
+// the JSON-encoded event batch with a correct X-Spid-Signature header.
+func TestWebhookAlerterSignsAndPostsBody(t *testing.T) {
+	secret := []byte("s3cret")
+	var gotBody []byte
+	var gotSig string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		gotBody, err = io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotSig = r.Header.Get("X-Spid-Signature")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	a := &webhookAlerter{url: srv.URL, secret: secret, client: srv.Client()}
+	evs := []Event{{Evtype: evModify, File: "/tmp/bar"}}
+	if err := a.Alert(evs); err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(gotBody)
+	wantSig := hex.EncodeToString(mac.Sum(nil))
+	if gotSig != wantSig {
+		t.Fatalf("signature mismatch: got %v want %v", gotSig, wantSig)
+	}
+
+	var decoded []Event
+	if err := json.Unmarshal(gotBody, &decoded); err != nil {
+		t.Fatalf("posted body was not the JSON event batch: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0].File != "/tmp/bar" {
+		t.Fatalf("unexpected posted events: %+v", decoded)
+	}
+}
+
+// TestWebhookAlerterErrorsOnNonSuccessStatus verifies that a non-2xx
+// response is reported as an error rather than being treated as delivered.
+func TestWebhookAlerterErrorsOnNonSuccessStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	a := &webhookAlerter{url: srv.URL, secret: []byte("s3cret"), client: srv.Client()}
+	if err := a.Alert([]Event{{Evtype: evModify, File: "/tmp/bar"}}); err == nil {
+		t.Fatal("expected an error for a 500 response")
+	}
+}
@@ -0,0 +1,94 @@
+package sentinel
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Duration wraps time.Duration so it can be configured in a Config's JSON
+// as a string like "30s" or "5m" instead of a raw nanosecond count.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Daemon keeps a Sentinel unlocked in memory and runs Scan on a fixed
+// interval, dispatching any resulting events to a set of Alerter sinks and
+// periodically flushing the sentinel back to disk.
+type Daemon struct {
+	sentinel *Sentinel
+	dbPath   string
+	password string
+	keyfiles []string
+	interval time.Duration
+	alerters []Alerter
+}
+
+// NewDaemon creates a Daemon that scans s every interval, persisting to
+// dbPath with password (and keyfiles, if any) on every scan that completes.
+// Keyfiles are always recombined using s.KeyfilesOrdered, the mode s was
+// opened with, so a daemon can never resave a database in the wrong mode.
+func NewDaemon(s *Sentinel, dbPath string, password string, keyfiles []string, interval time.Duration, alerters []Alerter) *Daemon {
+	return &Daemon{
+		sentinel: s,
+		dbPath:   dbPath,
+		password: password,
+		keyfiles: keyfiles,
+		interval: interval,
+		alerters: alerters,
+	}
+}
+
+// Run blocks, scanning the daemon's sentinel on its configured interval
+// until ctx is cancelled. Regardless of how it returns, the sentinel is
+// flushed to disk first so a SIGTERM never loses a completed scan.
+func (d *Daemon) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return d.save()
+		case <-ticker.C:
+			if err := d.tick(); err != nil {
+				d.save()
+				return err
+			}
+		}
+	}
+}
+
+// tick runs a single scan, dispatches any events to the configured
+// alerters, and flushes the result to disk.
+func (d *Daemon) tick() error {
+	evs, err := d.sentinel.Scan()
+	if err != nil {
+		return err
+	}
+	if len(evs) > 0 {
+		for _, a := range d.alerters {
+			if err := a.Alert(evs); err != nil {
+				log.Printf("sentinel: alerter failed: %v", err)
+			}
+		}
+	}
+	return d.save()
+}
+
+func (d *Daemon) save() error {
+	return d.sentinel.Save(d.dbPath, d.password, d.keyfiles)
+}
@@ -0,0 +1,131 @@
+package sentinel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAlerter records every batch of events it's asked to deliver.
+type fakeAlerter struct {
+	mu    sync.Mutex
+	calls [][]Event
+}
+
+func (a *fakeAlerter) Alert(evs []Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, evs)
+	return nil
+}
+
+func (a *fakeAlerter) callCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.calls)
+}
+
+// TestDaemonTickAlertsAndSaves verifies that a single tick scans the
+// sentinel, dispatches any detected events to its alerters, and persists
+// the result so it can be reopened with the same password and keyfiles.
+func TestDaemonTickAlertsAndSaves(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "watched")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createRandFile(filepath.Join(dir, "file1"), 64); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(Config{WatchFiles: []string{dir}})
+	if _, err := s.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(root, "spid.db")
+	if err := s.Save(dbPath, "password", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	alerter := &fakeAlerter{}
+	d := NewDaemon(s, dbPath, "password", nil, time.Hour, []Alerter{alerter})
+
+	if err := d.tick(); err != nil {
+		t.Fatal(err)
+	}
+	if alerter.callCount() != 0 {
+		t.Fatalf("expected no alerts on an unchanged tree, got %d", alerter.callCount())
+	}
+
+	if _, err := createRandFile(filepath.Join(dir, "file2"), 64); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.tick(); err != nil {
+		t.Fatal(err)
+	}
+	if alerter.callCount() != 1 {
+		t.Fatalf("expected one alert batch after a new file appeared, got %d", alerter.callCount())
+	}
+
+	if _, err := Open(dbPath, "password", false, nil); err != nil {
+		t.Fatalf("could not reopen database saved by tick: %v", err)
+	}
+}
+
+// TestDaemonRunStopsOnCancel verifies that Run ticks on its configured
+// interval, dispatching events to alerters, and returns once ctx is
+// cancelled.
+func TestDaemonRunStopsOnCancel(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "watched")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createRandFile(filepath.Join(dir, "file1"), 64); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(Config{WatchFiles: []string{dir}})
+	if _, err := s.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(root, "spid.db")
+	if err := s.Save(dbPath, "password", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	alerter := &fakeAlerter{}
+	d := NewDaemon(s, dbPath, "password", nil, 20*time.Millisecond, []Alerter{alerter})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Run(ctx)
+	}()
+
+	if err := os.WriteFile(filepath.Join(dir, "file1"), []byte("changed contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for alerter.callCount() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for Run to pick up a change and alert")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned an error on cancellation: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after ctx cancellation")
+	}
+}
@@ -0,0 +1,90 @@
+package sentinel
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/HACKERALERT/infectious"
+)
+
+const (
+	// fecChunkSize is the number of ciphertext bytes each FEC shard
+	// protects independently.
+	fecChunkSize = 128
+	// fecShardSize is the size of the encoded shard produced from a single
+	// fecChunkSize-byte chunk: the original bytes plus 8 parity bytes,
+	// enough for the underlying (128,136) code to correct up to 4
+	// corrupted bytes per chunk.
+	fecShardSize = 136
+)
+
+// fecEncode pads data to a multiple of fecChunkSize and returns the
+// FEC-protected shard stream written by Save, one fecShardSize shard per
+// chunk of plaintext ciphertext.
+func fecEncode(data []byte) ([]byte, error) {
+	f, err := infectious.NewFEC(fecChunkSize, fecShardSize)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := padToChunk(data)
+	var out bytes.Buffer
+	for i := 0; i < len(padded); i += fecChunkSize {
+		chunk := padded[i : i+fecChunkSize]
+		shard := make([]byte, fecShardSize)
+		err := f.Encode(chunk, func(s infectious.Share) {
+			shard[s.Number] = s.Data[0]
+		})
+		if err != nil {
+			return nil, err
+		}
+		out.Write(shard)
+	}
+	return out.Bytes(), nil
+}
+
+// fecDecode reverses fecEncode, reading shardData fecShardSize bytes at a
+// time and running the Reed-Solomon decoder per chunk to repair corrupted
+// bytes. It returns
+// the reconstructed ciphertext and the number of chunks that required
+// repair. If fix is false, any chunk needing repair is logged but still
+// corrected; callers should treat the database as suspect in that case.
+func fecDecode(shardData []byte, fix bool) ([]byte, int, error) {
+	f, err := infectious.NewFEC(fecChunkSize, fecShardSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var out bytes.Buffer
+	var repaired int
+	for i := 0; i < len(shardData); i += fecShardSize {
+		shard := shardData[i : i+fecShardSize]
+		shares := make([]infectious.Share, fecShardSize)
+		for j := range shares {
+			shares[j] = infectious.Share{Number: j, Data: []byte{shard[j]}}
+		}
+		chunk, err := f.Decode(nil, shares)
+		if err != nil {
+			return nil, repaired, err
+		}
+		if !bytes.Equal(chunk, shard[:fecChunkSize]) {
+			repaired++
+			if !fix {
+				log.Printf("sentinel: repaired corrupted FEC chunk %d (pass -fix to suppress this warning)", i/fecShardSize)
+			}
+		}
+		out.Write(chunk)
+	}
+	return out.Bytes(), repaired, nil
+}
+
+// padToChunk pads data with zero bytes so its length is a multiple of
+// fecChunkSize, prefixing nothing: the unpadded length is recovered from the
+// SentinelFile's stored length field rather than from the padding itself.
+func padToChunk(data []byte) []byte {
+	rem := len(data) % fecChunkSize
+	if rem == 0 {
+		return data
+	}
+	return append(data, make([]byte, fecChunkSize-rem)...)
+}
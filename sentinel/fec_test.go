@@ -0,0 +1,61 @@
+package sentinel
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+// TestFECRoundTrip verifies that data survives an encode/decode cycle
+// unchanged when no corruption occurs.
+func TestFECRoundTrip(t *testing.T) {
+	data := make([]byte, fecChunkSize*3+17)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := fecEncode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, repaired, err := fecDecode(encoded, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repaired != 0 {
+		t.Fatal("expected no repairs for uncorrupted data, got", repaired)
+	}
+	if !bytes.Equal(decoded[:len(data)], data) {
+		t.Fatal("decoded data does not match original")
+	}
+}
+
+// TestFECRepairsCorruption verifies that a corrupted byte within a single
+// shard is transparently repaired by fecDecode.
+func TestFECRepairsCorruption(t *testing.T) {
+	data := make([]byte, fecChunkSize*2)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := fecEncode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// flip a byte in the middle of the first shard
+	encoded[fecChunkSize/2] ^= 0xff
+
+	decoded, repaired, err := fecDecode(encoded, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repaired != 1 {
+		t.Fatal("expected exactly one repaired chunk, got", repaired)
+	}
+	if !bytes.Equal(decoded[:len(data)], data) {
+		t.Fatal("decoded data does not match original after repair")
+	}
+}
@@ -0,0 +1,65 @@
+package sentinel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKeyfile(t *testing.T, dir, name string, contents []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestCombineKeyfileSecretOrdered verifies that ordered mode produces a
+// different secret when the same keyfiles are presented in a different
+// order.
+func TestCombineKeyfileSecretOrdered(t *testing.T) {
+	dir := t.TempDir()
+	a := writeKeyfile(t, dir, "a", []byte("keyfile a"))
+	b := writeKeyfile(t, dir, "b", []byte("keyfile b"))
+
+	forward, err := combineKeyfileSecret([]string{a, b}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backward, err := combineKeyfileSecret([]string{b, a}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forward == backward {
+		t.Fatal("ordered combination should differ when keyfile order differs")
+	}
+
+	again, err := combineKeyfileSecret([]string{a, b}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forward != again {
+		t.Fatal("ordered combination should be deterministic for the same order")
+	}
+}
+
+// TestCombineKeyfileSecretAnyOrder verifies that any-order mode produces the
+// same secret regardless of the order keyfiles are presented in.
+func TestCombineKeyfileSecretAnyOrder(t *testing.T) {
+	dir := t.TempDir()
+	a := writeKeyfile(t, dir, "a", []byte("keyfile a"))
+	b := writeKeyfile(t, dir, "b", []byte("keyfile b"))
+
+	forward, err := combineKeyfileSecret([]string{a, b}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backward, err := combineKeyfileSecret([]string{b, a}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forward != backward {
+		t.Fatal("any-order combination should not depend on keyfile order")
+	}
+}
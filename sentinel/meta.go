@@ -0,0 +1,69 @@
+package sentinel
+
+import (
+	"os"
+	"syscall"
+)
+
+// statMeta builds the ObjectMeta for path given the os.Lstat result already
+// obtained for it. Content is only checksummed for regular files; symlinks
+// and directories are tracked by type and metadata alone.
+func statMeta(path string, finfo os.FileInfo) (ObjectMeta, error) {
+	meta := ObjectMeta{
+		Mode:    finfo.Mode(),
+		Size:    finfo.Size(),
+		ModTime: finfo.ModTime(),
+	}
+
+	switch {
+	case finfo.Mode()&os.ModeSymlink != 0:
+		meta.Kind = kindSymlink
+	case finfo.IsDir():
+		meta.Kind = kindDir
+	default:
+		meta.Kind = kindFile
+	}
+
+	if sys, ok := finfo.Sys().(*syscall.Stat_t); ok {
+		meta.UID = sys.Uid
+		meta.GID = sys.Gid
+	}
+
+	if meta.Kind == kindFile {
+		cs, err := checksumFile(path)
+		if err != nil {
+			return ObjectMeta{}, err
+		}
+		meta.Checksum = cs
+	}
+
+	xattrs, err := readXattrs(path)
+	if err != nil {
+		return ObjectMeta{}, err
+	}
+	meta.Xattrs = xattrs
+
+	return meta, nil
+}
+
+// sameAttrs reports whether known and other describe the same permissions,
+// ownership, modification time, and extended attributes. Checksum and Kind
+// are compared separately by processEntry, since they get their own event
+// types (evModify, evReplace).
+func (known ObjectMeta) sameAttrs(other ObjectMeta) bool {
+	if known.Mode != other.Mode || known.UID != other.UID || known.GID != other.GID {
+		return false
+	}
+	if !known.ModTime.Equal(other.ModTime) {
+		return false
+	}
+	if len(known.Xattrs) != len(other.Xattrs) {
+		return false
+	}
+	for name, val := range known.Xattrs {
+		if other.Xattrs[name] != val {
+			return false
+		}
+	}
+	return true
+}
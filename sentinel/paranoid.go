@@ -0,0 +1,138 @@
+package sentinel
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"io"
+
+	"github.com/aead/serpent"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	argon2Time    = 4
+	argon2Memory  = 1 << 20 // KiB
+	argon2Threads = 4
+	argon2KeyLen  = 32
+)
+
+// paranoidSubkeys holds the independent keys derived from a single Argon2id
+// master key via HKDF-SHA3-256, one per stage of the paranoid cascade.
+type paranoidSubkeys struct {
+	chacha  [32]byte
+	serpent [32]byte
+	mac     [32]byte
+}
+
+// deriveParanoidKeys stretches password with Argon2id, folds in the secret
+// derived from any configured keyfiles, and splits the result into
+// independent subkeys for ChaCha20, Serpent, and the BLAKE2b MAC, so that a
+// weakness in one derived key can't be leveraged against another.
+func deriveParanoidKeys(password string, salt []byte, haveKeyfiles bool, keyfileSecret [32]byte) (paranoidSubkeys, error) {
+	master := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	if haveKeyfiles {
+		mixed, err := mixKeyfileSecret(master, keyfileSecret)
+		if err != nil {
+			return paranoidSubkeys{}, err
+		}
+		master = mixed
+	}
+
+	var keys paranoidSubkeys
+	if err := hkdfExpand(master, salt, "spid-paranoid-chacha20", keys.chacha[:]); err != nil {
+		return paranoidSubkeys{}, err
+	}
+	if err := hkdfExpand(master, salt, "spid-paranoid-serpent", keys.serpent[:]); err != nil {
+		return paranoidSubkeys{}, err
+	}
+	if err := hkdfExpand(master, salt, "spid-paranoid-mac", keys.mac[:]); err != nil {
+		return paranoidSubkeys{}, err
+	}
+	return keys, nil
+}
+
+func hkdfExpand(master, salt []byte, info string, out []byte) error {
+	r := hkdf.New(sha3.New256, master, salt, []byte(info))
+	_, err := io.ReadFull(r, out)
+	return err
+}
+
+// paranoidSeal encrypts plaintext with the paranoid cascade: ChaCha20
+// followed by Serpent in CTR mode, and authenticates the result with a
+// keyed BLAKE2b-512 MAC. It returns the ciphertext along with the nonces
+// and MAC paranoidOpen needs to reverse the process.
+func paranoidSeal(plaintext []byte, password string, salt []byte, keyfiles []string, keyfileSecret [32]byte) (sealed []byte, mac [64]byte, chachaNonce [12]byte, serpentNonce [16]byte, err error) {
+	keys, err := deriveParanoidKeys(password, salt, len(keyfiles) > 0, keyfileSecret)
+	if err != nil {
+		return nil, mac, chachaNonce, serpentNonce, err
+	}
+	if _, err = io.ReadFull(rand.Reader, chachaNonce[:]); err != nil {
+		return nil, mac, chachaNonce, serpentNonce, err
+	}
+	if _, err = io.ReadFull(rand.Reader, serpentNonce[:]); err != nil {
+		return nil, mac, chachaNonce, serpentNonce, err
+	}
+
+	chachaStream, err := chacha20.NewUnauthenticatedCipher(keys.chacha[:], chachaNonce[:])
+	if err != nil {
+		return nil, mac, chachaNonce, serpentNonce, err
+	}
+	stage1 := make([]byte, len(plaintext))
+	chachaStream.XORKeyStream(stage1, plaintext)
+
+	block, err := serpent.NewCipher(keys.serpent[:])
+	if err != nil {
+		return nil, mac, chachaNonce, serpentNonce, err
+	}
+	sealed = make([]byte, len(stage1))
+	cipher.NewCTR(block, serpentNonce[:]).XORKeyStream(sealed, stage1)
+
+	h, err := blake2b.New512(keys.mac[:])
+	if err != nil {
+		return nil, mac, chachaNonce, serpentNonce, err
+	}
+	h.Write(sealed)
+	copy(mac[:], h.Sum(nil))
+
+	return sealed, mac, chachaNonce, serpentNonce, nil
+}
+
+// paranoidOpen reverses paranoidSeal, verifying the BLAKE2b-512 MAC in
+// constant time before either decryption stage is attempted.
+func paranoidOpen(sealed []byte, password string, salt []byte, mac [64]byte, chachaNonce [12]byte, serpentNonce [16]byte, keyfiles []string, keyfileSecret [32]byte) ([]byte, error) {
+	keys, err := deriveParanoidKeys(password, salt, len(keyfiles) > 0, keyfileSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := blake2b.New512(keys.mac[:])
+	if err != nil {
+		return nil, err
+	}
+	h.Write(sealed)
+	if subtle.ConstantTimeCompare(h.Sum(nil), mac[:]) != 1 {
+		return nil, errors.New("sentinel: paranoid MAC verification failed")
+	}
+
+	block, err := serpent.NewCipher(keys.serpent[:])
+	if err != nil {
+		return nil, err
+	}
+	stage1 := make([]byte, len(sealed))
+	cipher.NewCTR(block, serpentNonce[:]).XORKeyStream(stage1, sealed)
+
+	chachaStream, err := chacha20.NewUnauthenticatedCipher(keys.chacha[:], chachaNonce[:])
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(stage1))
+	chachaStream.XORKeyStream(plaintext, stage1)
+
+	return plaintext, nil
+}
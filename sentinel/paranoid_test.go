@@ -0,0 +1,55 @@
+package sentinel
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+// TestParanoidSealOpenRoundTrip verifies that paranoidOpen recovers exactly
+// what paranoidSeal sealed, through the full ChaCha20+Serpent+BLAKE2b
+// cascade.
+func TestParanoidSealOpenRoundTrip(t *testing.T) {
+	plaintext := make([]byte, 4096)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		t.Fatal(err)
+	}
+	salt := make([]byte, 24)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, mac, chachaNonce, serpentNonce, err := paranoidSeal(plaintext, "correct horse battery staple", salt, nil, [32]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := paranoidOpen(sealed, "correct horse battery staple", salt, mac, chachaNonce, serpentNonce, nil, [32]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatal("paranoidOpen did not recover the original plaintext")
+	}
+}
+
+// TestParanoidOpenWrongPassword verifies that the BLAKE2b MAC check rejects
+// the wrong password instead of silently returning garbage.
+func TestParanoidOpenWrongPassword(t *testing.T) {
+	plaintext := []byte("secret data")
+	salt := make([]byte, 24)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, mac, chachaNonce, serpentNonce, err := paranoidSeal(plaintext, "rightpass", salt, nil, [32]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = paranoidOpen(sealed, "wrongpass", salt, mac, chachaNonce, serpentNonce, nil, [32]byte{})
+	if err == nil {
+		t.Fatal("expected an error opening with the wrong password")
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"encoding/hex"
 	"errors"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/nacl/secretbox"
@@ -20,9 +22,37 @@ type (
 	// Sentinel is a Scanner that can be used to securely detect and record file
 	// integrity changes for a set of files.
 	Sentinel struct {
-		WatchFiles   []string
+		WatchFiles []string
+		PriorScans []Scan
+
+		// Objects holds the last-known metadata for every watched path.
+		// Version tracks the schema Objects (and the now-deprecated
+		// KnownObjects) were encoded with, so Open can migrate older
+		// databases forward.
+		Objects map[string]ObjectMeta
+		Version int
+
+		// KnownObjects is the pre-chunk6 checksum-only map. It is only
+		// ever populated by decoding an old database; migrate empties it
+		// into Objects on first Open.
 		KnownObjects map[string]checksum
-		PriorScans   []Scan
+
+		// Paranoid upgrades the crypto stack used by Save/Open from
+		// scrypt+secretbox to Argon2id plus a ChaCha20/Serpent cascade,
+		// for deployments that want margin against future cryptanalysis
+		// of either primitive.
+		Paranoid bool
+
+		// KeyfilesOrdered records whether this database's keyfile secret
+		// was combined in ordered (chained) or any-order (XORed) mode, so
+		// that resaving it always recombines keyfiles the same way it was
+		// originally created with, regardless of what a caller passes to
+		// Save.
+		KeyfilesOrdered bool
+
+		// mu guards Objects against concurrent access from Watch and Scan
+		// running at the same time.
+		mu sync.Mutex
 	}
 
 	// Event defines a file integrity Event.
@@ -33,6 +63,21 @@ type (
 		File         string
 	}
 
+	// ObjectMeta is everything spid remembers about a single watched path
+	// between scans: its content checksum (for regular files), its type,
+	// and the permission/ownership/xattr metadata an attacker might tamper
+	// with without touching file content.
+	ObjectMeta struct {
+		Checksum checksum
+		Kind     string
+		Mode     os.FileMode
+		UID      uint32
+		GID      uint32
+		Size     int64
+		ModTime  time.Time
+		Xattrs   map[string]string
+	}
+
 	// Scan stores the information from a single sentinal Scan Event.
 	Scan struct {
 		Timestamp time.Time
@@ -42,36 +87,108 @@ type (
 	// Config defines the configuration for a sentinel.
 	Config struct {
 		WatchFiles []string
+		Paranoid   bool
+
+		// Keyfiles lists paths that must be supplied alongside the
+		// password to unlock the database, splitting trust between
+		// something the operator knows and something they hold.
+		Keyfiles []string
+		// KeyfilesOrdered requires Keyfiles to be presented in this same
+		// order on every Save/Open; otherwise they may be given in any
+		// order.
+		KeyfilesOrdered bool
+
+		// ScanInterval is how often a Daemon scans WatchFiles. It is
+		// encoded in JSON as a duration string, e.g. "30s" or "5m".
+		ScanInterval Duration
+		// Alerters lists the sinks a Daemon dispatches non-empty scan
+		// results to.
+		Alerters []AlerterConfig
 	}
 
-	// SentinelFile stores the data used to encode a sentinel.
+	// SentinelFile stores the data used to encode a sentinel. Version
+	// determines how Data is laid out on disk so that databases written by
+	// older versions of spid continue to open. ChaChaNonce, SerpentNonce,
+	// and MAC are only populated when Version is sentinelVersionParanoid.
+	// KeyfilesOrdered only matters to callers that supply keyfiles; it
+	// records whether they must be presented in the original order.
 	SentinelFile struct {
-		Data  []byte
-		Nonce [24]byte
-		Salt  [24]byte
+		Version         byte
+		Data            []byte
+		DataLen         int
+		Nonce           [24]byte
+		Salt            [24]byte
+		ChaChaNonce     [12]byte
+		SerpentNonce    [16]byte
+		MAC             [64]byte
+		KeyfilesOrdered bool
 	}
 
 	checksum string
 )
 
 const (
-	evCreate = "EV_CREATE"
-	evModify = "EV_MODIFY"
+	evCreate  = "EV_CREATE"
+	evModify  = "EV_MODIFY"
+	evDelete  = "EV_DELETE"
+	evAttr    = "EV_ATTR"
+	evReplace = "EV_REPLACE"
+
+	kindFile    = "file"
+	kindDir     = "dir"
+	kindSymlink = "symlink"
 
 	scryptN = 16384
 	scryptP = 1
 	scryptR = 8
 	keyLen  = 32
+
+	// sentinelVersionLegacy is a bare secretbox-sealed blob with no forward
+	// error correction, as written by spid before FEC support existed.
+	sentinelVersionLegacy = 0
+	// sentinelVersionFEC stores Data as a stream of FEC shards protecting
+	// the secretbox-sealed blob against bit rot.
+	sentinelVersionFEC = 1
+	// sentinelVersionParanoid stores Data as FEC shards protecting a blob
+	// sealed with the Argon2id/ChaCha20+Serpent paranoid cascade.
+	sentinelVersionParanoid = 2
+
+	// sentinelSchemaObjectMeta is the Sentinel.Version written by spid
+	// once KnownObjects was replaced by the richer Objects map. Databases
+	// decoded with Version less than this are migrated in place by
+	// migrate on Open.
+	sentinelSchemaObjectMeta = 2
 )
 
 // New creates a new sentinel using the options specified in config.
 func New(config Config) *Sentinel {
 	return &Sentinel{
-		WatchFiles:   config.WatchFiles,
-		KnownObjects: make(map[string]checksum),
+		WatchFiles:      config.WatchFiles,
+		Objects:         make(map[string]ObjectMeta),
+		Version:         sentinelSchemaObjectMeta,
+		Paranoid:        config.Paranoid,
+		KeyfilesOrdered: config.KeyfilesOrdered,
 	}
 }
 
+// migrate upgrades a Sentinel decoded from an older on-disk schema in
+// place. Pre-chunk6 databases only ever populated KnownObjects with a bare
+// checksum per path; migrate promotes those entries into Objects so Scan
+// can start tracking their full metadata from the next pass.
+func (s *Sentinel) migrate() {
+	if s.Version >= sentinelSchemaObjectMeta {
+		return
+	}
+	if s.Objects == nil {
+		s.Objects = make(map[string]ObjectMeta)
+	}
+	for path, cs := range s.KnownObjects {
+		s.Objects[path] = ObjectMeta{Checksum: cs, Kind: kindFile}
+	}
+	s.KnownObjects = nil
+	s.Version = sentinelSchemaObjectMeta
+}
+
 // checksumFile returns the sha256 checksum of the file at the provided path.
 func checksumFile(path string) (checksum, error) {
 	f, err := os.Open(path)
@@ -90,55 +207,84 @@ func checksumFile(path string) (checksum, error) {
 }
 
 // process returns a slice of events produced by scanning the supplied
-// filename. filenames which are directories are scanned recursively.
-func (s *Sentinel) process(filename string) ([]Event, error) {
-	var evs []Event
+// filename, recording every path it visits into seen so Scan can tell which
+// previously known paths have since been deleted. filenames which are
+// directories are scanned recursively.
+func (s *Sentinel) process(filename string, seen map[string]struct{}) ([]Event, error) {
+	finfo, err := os.Lstat(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// filename vanished between its parent directory being listed
+			// and this lstat -- a benign race (logrotate, a tmpfile
+			// cleanup, Watch's own rename handling) rather than a scan
+			// failure. Leave it out of seen so deletedEvents reports it
+			// as evDelete instead of aborting the whole scan.
+			return nil, nil
+		}
+		return nil, err
+	}
 
-	finfo, err := os.Stat(filename)
+	seen[filename] = struct{}{}
+	evs, err := s.processEntry(filename, finfo)
 	if err != nil {
 		return nil, err
 	}
-	if finfo.IsDir() {
-		err = filepath.Walk(filename, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if path == filename {
+
+	if !finfo.IsDir() {
+		return evs, nil
+	}
+
+	err = filepath.Walk(filename, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
 				return nil
 			}
-			subevs, err := s.process(path)
-			if err != nil {
-				return err
-			}
-			evs = append(evs, subevs...)
+			return err
+		}
+		if path == filename {
 			return nil
-		})
+		}
+		subevs, err := s.process(path, seen)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		return evs, nil
+		evs = append(evs, subevs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return evs, nil
+}
 
-	checksum, err := checksumFile(filename)
+// processEntry compares the current metadata of a single path against what
+// was recorded for it last time, returning the single Event (if any) that
+// describes the difference, and updating Objects to match.
+func (s *Sentinel) processEntry(path string, finfo os.FileInfo) ([]Event, error) {
+	meta, err := statMeta(path, finfo)
 	if err != nil {
 		return nil, err
 	}
-	knownChecksum, seen := s.KnownObjects[filename]
-	if !seen {
-		evs = append(evs, Event{
-			Evtype:      evCreate,
-			NewChecksum: checksum,
-			File:        filename,
-		})
-	} else if knownChecksum != checksum {
-		evs = append(evs, Event{
-			Evtype:       evModify,
-			OrigChecksum: knownChecksum,
-			NewChecksum:  checksum,
-			File:         filename,
-		})
-	}
-	s.KnownObjects[filename] = checksum
+
+	s.mu.Lock()
+	known, seen := s.Objects[path]
+	s.mu.Unlock()
+
+	var evs []Event
+	switch {
+	case !seen:
+		evs = append(evs, Event{Evtype: evCreate, NewChecksum: meta.Checksum, File: path})
+	case known.Kind != meta.Kind:
+		evs = append(evs, Event{Evtype: evReplace, OrigChecksum: known.Checksum, NewChecksum: meta.Checksum, File: path})
+	case known.Checksum != meta.Checksum:
+		evs = append(evs, Event{Evtype: evModify, OrigChecksum: known.Checksum, NewChecksum: meta.Checksum, File: path})
+	case !known.sameAttrs(meta):
+		evs = append(evs, Event{Evtype: evAttr, OrigChecksum: known.Checksum, NewChecksum: meta.Checksum, File: path})
+	}
+
+	s.mu.Lock()
+	s.Objects[path] = meta
+	s.mu.Unlock()
 
 	return evs, nil
 }
@@ -147,23 +293,71 @@ func (s *Sentinel) process(filename string) ([]Event, error) {
 // Events.
 func (s *Sentinel) Scan() ([]Event, error) {
 	var evs []Event
+	seen := make(map[string]struct{})
 	for _, wf := range s.WatchFiles {
-		ev, err := s.process(wf)
+		ev, err := s.process(wf, seen)
 		if err != nil {
 			return nil, err
 		}
 		evs = append(evs, ev...)
 	}
+	evs = append(evs, s.deletedEvents(seen)...)
+	s.recordScan(evs)
+	return evs, nil
+}
+
+// deletedEvents returns an evDelete Event, and stops tracking, for every
+// previously known path that wasn't observed in this pass's seen set.
+func (s *Sentinel) deletedEvents(seen map[string]struct{}) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var evs []Event
+	for path, meta := range s.Objects {
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		evs = append(evs, Event{Evtype: evDelete, OrigChecksum: meta.Checksum, File: path})
+		delete(s.Objects, path)
+	}
+	return evs
+}
+
+// removeKnownObject stops tracking path and returns the evDelete Event
+// describing its removal, if it was previously known. Watch uses this to
+// react to a kernel delete/rename event without waiting for the next Scan.
+func (s *Sentinel) removeKnownObject(path string) (Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta, ok := s.Objects[path]
+	if !ok {
+		return Event{}, false
+	}
+	delete(s.Objects, path)
+	return Event{Evtype: evDelete, OrigChecksum: meta.Checksum, File: path}, true
+}
+
+// recordScan appends evs to PriorScans as a single Scan, timestamped now.
+// Called by both Scan and Watch so manual and event-driven monitoring share
+// one history.
+func (s *Sentinel) recordScan(evs []Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.PriorScans = append(s.PriorScans, Scan{
 		Timestamp: time.Now(),
 		Events:    evs,
 	})
-	return evs, nil
 }
 
 // Save writes the sentinel to the path supplied in `path`, encrypting the
-// sentinel using the password provided to `password.
-func (s *Sentinel) Save(path string, password string) error {
+// sentinel using the password provided to `password` and, if keyfiles is
+// non-empty, the secret derived from hashing those keyfiles. Keyfiles are
+// always combined using s.KeyfilesOrdered -- the mode the database was
+// created with -- rather than anything the caller might otherwise assume,
+// so resaving never silently changes how the keyfile secret is derived.
+// The sealed database is protected against bit rot with Reed-Solomon
+// forward error correction before being written to disk.
+func (s *Sentinel) Save(path string, password string, keyfiles []string) error {
 	var nonce, salt [24]byte
 	_, err := io.ReadFull(rand.Reader, nonce[:])
 	if err != nil {
@@ -173,12 +367,6 @@ func (s *Sentinel) Save(path string, password string) error {
 	if err != nil {
 		return err
 	}
-	var secret [32]byte
-	key, err := scrypt.Key([]byte(password), salt[:], scryptN, scryptR, scryptP, keyLen)
-	if err != nil {
-		return err
-	}
-	copy(secret[:], key)
 
 	var encoded bytes.Buffer
 	err = gob.NewEncoder(&encoded).Encode(s)
@@ -186,8 +374,45 @@ func (s *Sentinel) Save(path string, password string) error {
 		return err
 	}
 
-	data := secretbox.Seal(nonce[:], encoded.Bytes(), &nonce, &secret)
-	out := SentinelFile{Data: data, Nonce: nonce, Salt: salt}
+	var keyfileSecret [32]byte
+	if len(keyfiles) > 0 {
+		keyfileSecret, err = combineKeyfileSecret(keyfiles, s.KeyfilesOrdered)
+		if err != nil {
+			return err
+		}
+	}
+
+	var sealed []byte
+	out := SentinelFile{Nonce: nonce, Salt: salt, KeyfilesOrdered: s.KeyfilesOrdered}
+	if s.Paranoid {
+		out.Version = sentinelVersionParanoid
+		sealed, out.MAC, out.ChaChaNonce, out.SerpentNonce, err = paranoidSeal(encoded.Bytes(), password, salt[:], keyfiles, keyfileSecret)
+		if err != nil {
+			return err
+		}
+	} else {
+		var secret [32]byte
+		key, err := scrypt.Key([]byte(password), salt[:], scryptN, scryptR, scryptP, keyLen)
+		if err != nil {
+			return err
+		}
+		copy(secret[:], key)
+		if len(keyfiles) > 0 {
+			mixed, err := mixKeyfileSecret(secret[:], keyfileSecret)
+			if err != nil {
+				return err
+			}
+			copy(secret[:], mixed)
+		}
+		out.Version = sentinelVersionFEC
+		sealed = secretbox.Seal(nonce[:], encoded.Bytes(), &nonce, &secret)
+	}
+
+	out.DataLen = len(sealed)
+	out.Data, err = fecEncode(sealed)
+	if err != nil {
+		return err
+	}
 	// TODO: this is unsafe, since a write can partially complete. Switch to
 	// write temp/rename for better atomicity.
 	f, err := os.Create(path)
@@ -198,8 +423,12 @@ func (s *Sentinel) Save(path string, password string) error {
 	return gob.NewEncoder(f).Encode(out)
 }
 
-// Open loads a sentinel from disk using the supplied `path` and `password`.
-func Open(path string, password string) (*Sentinel, error) {
+// Open loads a sentinel from disk using the supplied `path`, `password`, and
+// (if the database was created with any) `keyfiles`. If fix is true, any
+// FEC chunks that needed repair are tolerated and reported with a single
+// summary log line; otherwise each one is logged as a warning since they
+// indicate disk corruption occurred.
+func Open(path string, password string, fix bool, keyfiles []string) (*Sentinel, error) {
 	var in SentinelFile
 	f, err := os.Open(path)
 	if err != nil {
@@ -210,20 +439,65 @@ func Open(path string, password string) (*Sentinel, error) {
 	if err != nil {
 		return nil, err
 	}
-	var secret [32]byte
-	key, err := scrypt.Key([]byte(password), in.Salt[:], scryptN, scryptR, scryptP, keyLen)
-	if err != nil {
-		return nil, err
+
+	sealed := in.Data
+	if in.Version != sentinelVersionLegacy {
+		var repaired int
+		sealed, repaired, err = fecDecode(in.Data, fix)
+		if err != nil {
+			return nil, err
+		}
+		sealed = sealed[:in.DataLen]
+		if repaired > 0 && fix {
+			log.Printf("sentinel: repaired %d corrupted FEC chunk(s) in %v", repaired, path)
+		}
+	}
+
+	var keyfileSecret [32]byte
+	if len(keyfiles) > 0 {
+		keyfileSecret, err = combineKeyfileSecret(keyfiles, in.KeyfilesOrdered)
+		if err != nil {
+			return nil, err
+		}
 	}
-	copy(secret[:], key)
-	decryptedData, success := secretbox.Open([]byte{}, in.Data[len(in.Nonce):], &in.Nonce, &secret)
-	if !success {
-		return nil, errors.New("could not decrypt sentinel")
+
+	var decryptedData []byte
+	if in.Version == sentinelVersionParanoid {
+		decryptedData, err = paranoidOpen(sealed, password, in.Salt[:], in.MAC, in.ChaChaNonce, in.SerpentNonce, keyfiles, keyfileSecret)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var secret [32]byte
+		key, err := scrypt.Key([]byte(password), in.Salt[:], scryptN, scryptR, scryptP, keyLen)
+		if err != nil {
+			return nil, err
+		}
+		copy(secret[:], key)
+		if len(keyfiles) > 0 {
+			mixed, err := mixKeyfileSecret(secret[:], keyfileSecret)
+			if err != nil {
+				return nil, err
+			}
+			copy(secret[:], mixed)
+		}
+		var success bool
+		decryptedData, success = secretbox.Open([]byte{}, sealed[len(in.Nonce):], &in.Nonce, &secret)
+		if !success {
+			return nil, errors.New("could not decrypt sentinel")
+		}
 	}
+
 	var s Sentinel
 	err = gob.NewDecoder(bytes.NewBuffer(decryptedData)).Decode(&s)
 	if err != nil {
 		return nil, err
 	}
+	// in.KeyfilesOrdered is the authoritative record of how this database's
+	// keyfile secret was combined; reapply it even though it's also part of
+	// the gob-encoded Sentinel, so a database written before this field
+	// existed still resaves with the mode it was actually created with.
+	s.KeyfilesOrdered = in.KeyfilesOrdered
+	s.migrate()
 	return &s, nil
 }
@@ -49,13 +49,15 @@ func TestWatchFileDirectory(t *testing.T) {
 		defer os.RemoveAll(f.Name())
 		fileHandles = append(fileHandles, f)
 	}
-	s := New(Config{[]string{dir}})
+	s := New(Config{WatchFiles: []string{dir}})
 	evs, err := s.Scan()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(evs) != 4 {
-		t.Fatal("wrong number of events, got", len(evs), "wanted 4")
+	// dir, file1, file2, testsubdir, file3, and file4 are all tracked
+	// objects now, so each gets its own creation event.
+	if len(evs) != 6 {
+		t.Fatal("wrong number of events, got", len(evs), "wanted 6")
 	}
 }
 
@@ -86,7 +88,7 @@ func TestSentinel(t *testing.T) {
 	}
 
 	// construct a sentinel and watch those files.
-	s := New(Config{files})
+	s := New(Config{WatchFiles: files})
 
 	// first scan should result in 3 creation events
 	ev, err := s.Scan()
@@ -152,13 +154,13 @@ func TestSentinel(t *testing.T) {
 	}
 
 	// save the sentinel and reload it, verifying Save/Open function correctly
-	err = s.Save("testout", "testpass")
+	err = s.Save("testout", "testpass", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer os.Remove("testout")
 
-	s2, err := Open("testout", "testpass")
+	s2, err := Open("testout", "testpass", false, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -168,7 +170,7 @@ func TestSentinel(t *testing.T) {
 	if len(s2.WatchFiles) != len(s.WatchFiles) {
 		t.Fatal("wrong number of watch files after open")
 	}
-	if len(s2.KnownObjects) != len(s.KnownObjects) {
+	if len(s2.Objects) != len(s.Objects) {
 		t.Fatal("wrong number of known objects after open")
 	}
 
@@ -177,3 +179,217 @@ func TestSentinel(t *testing.T) {
 		t.Fatal("wrong number of prior scans: ", len(s.PriorScans), " wanted 4")
 	}
 }
+
+// TestSaveOpenParanoid verifies that a paranoid-mode sentinel round-trips
+// through Save/Open using the Argon2id/ChaCha20+Serpent cascade instead of
+// the default scrypt+secretbox path.
+func TestSaveOpenParanoid(t *testing.T) {
+	f, err := ioutil.TempFile("", "paranoidfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.CopyN(f, rand.Reader, 512); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s := New(Config{WatchFiles: []string{f.Name()}, Paranoid: true})
+	if _, err := s.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Save("testout-paranoid", "testpass", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("testout-paranoid")
+
+	s2, err := Open("testout-paranoid", "testpass", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s2.Objects) != len(s.Objects) {
+		t.Fatal("wrong number of known objects after paranoid open")
+	}
+
+	if _, err := Open("testout-paranoid", "wrongpass", false, nil); err == nil {
+		t.Fatal("expected an error opening a paranoid database with the wrong password")
+	}
+}
+
+// TestSaveOpenKeepsOriginalKeyfileOrder verifies that a database created
+// with ordered keyfiles stays openable with those same keyfiles in order
+// after a resave, even though Save no longer takes an ordered argument a
+// caller could get wrong.
+func TestSaveOpenKeepsOriginalKeyfileOrder(t *testing.T) {
+	dir := t.TempDir()
+	kfA := writeKeyfile(t, dir, "a", []byte("keyfile a"))
+	kfB := writeKeyfile(t, dir, "b", []byte("keyfile b"))
+	keyfiles := []string{kfA, kfB}
+
+	s := New(Config{KeyfilesOrdered: true})
+	if err := s.Save("testout-ordered", "testpass", keyfiles); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("testout-ordered")
+
+	s2, err := Open("testout-ordered", "testpass", false, keyfiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s2.KeyfilesOrdered {
+		t.Fatal("Open did not report the database as ordered")
+	}
+
+	// Resave, simulating scanCmd/Daemon: no ordered argument to get wrong.
+	if err := s2.Save("testout-ordered", "testpass", keyfiles); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open("testout-ordered", "testpass", false, keyfiles); err != nil {
+		t.Fatal("database no longer opens with its original keyfile order after resave:", err)
+	}
+	reversed := []string{kfB, kfA}
+	if _, err := Open("testout-ordered", "testpass", false, reversed); err == nil {
+		t.Fatal("expected opening an ordered database with reversed keyfiles to fail")
+	}
+}
+
+// TestMigrateLegacyKnownObjects verifies that a pre-chunk6 Sentinel decoded
+// with only KnownObjects populated is promoted into Objects on migrate.
+func TestMigrateLegacyKnownObjects(t *testing.T) {
+	s := &Sentinel{
+		KnownObjects: map[string]checksum{"file1": "abc123"},
+	}
+	s.migrate()
+
+	if s.Version != sentinelSchemaObjectMeta {
+		t.Fatal("migrate did not bump Version, got", s.Version)
+	}
+	if s.KnownObjects != nil {
+		t.Fatal("migrate should clear KnownObjects")
+	}
+	meta, ok := s.Objects["file1"]
+	if !ok {
+		t.Fatal("migrate did not promote file1 into Objects")
+	}
+	if meta.Checksum != "abc123" || meta.Kind != kindFile {
+		t.Fatal("migrate produced incorrect ObjectMeta:", meta)
+	}
+}
+
+// TestScanDetectsDeleteAttrAndReplace verifies that Scan reports evDelete
+// for a removed watched file, evAttr for a permission-only change, and
+// evReplace when a path's kind changes out from under it.
+func TestScanDetectsDeleteAttrAndReplace(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	deletedPath := filepath.Join(dir, "deleted")
+	attrPath := filepath.Join(dir, "attr")
+	replacedPath := filepath.Join(dir, "replaced")
+
+	if _, err := createRandFile(deletedPath, 64); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createRandFile(attrPath, 64); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createRandFile(replacedPath, 64); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(Config{WatchFiles: []string{deletedPath, attrPath, replacedPath}})
+	if _, err := s.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(deletedPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(attrPath, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(replacedPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(replacedPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	evs, err := s.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byFile := make(map[string]Event)
+	for _, ev := range evs {
+		byFile[ev.File] = ev
+	}
+
+	if ev, ok := byFile[deletedPath]; !ok || ev.Evtype != evDelete {
+		t.Fatal("expected evDelete for", deletedPath, "got", byFile[deletedPath])
+	}
+	if ev, ok := byFile[attrPath]; !ok || ev.Evtype != evAttr {
+		t.Fatal("expected evAttr for", attrPath, "got", byFile[attrPath])
+	}
+	if ev, ok := byFile[replacedPath]; !ok || ev.Evtype != evReplace {
+		t.Fatal("expected evReplace for", replacedPath, "got", byFile[replacedPath])
+	}
+}
+
+// TestScanToleratesConcurrentDeletionWithinWatchedDir verifies that a file
+// disappearing from inside a watched directory partway through Scan (the
+// TOCTOU window between filepath.Walk's readdir and process's own Lstat)
+// is reported as evDelete rather than aborting the whole scan with ENOENT.
+func TestScanToleratesConcurrentDeletionWithinWatchedDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	racing := filepath.Join(dir, "racing")
+	if _, err := createRandFile(racing, 64); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(Config{WatchFiles: []string{dir}})
+	if _, err := s.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	// process() is handed a path that no longer exists, simulating the
+	// race where filepath.Walk already listed racing but it was removed
+	// before process's own Lstat runs.
+	if err := os.Remove(racing); err != nil {
+		t.Fatal(err)
+	}
+	evs, err := s.process(racing, make(map[string]struct{}))
+	if err != nil {
+		t.Fatal("process should tolerate a vanished path, got error:", err)
+	}
+	if len(evs) != 0 {
+		t.Fatal("expected no events for a path process can no longer see, got", evs)
+	}
+
+	// Scan should now report racing as deleted instead of erroring out.
+	evs, err = s.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sawDelete bool
+	for _, ev := range evs {
+		if ev.File == racing && ev.Evtype == evDelete {
+			sawDelete = true
+		}
+	}
+	if !sawDelete {
+		t.Fatal("expected evDelete for", racing, "got", evs)
+	}
+}
+
@@ -0,0 +1,162 @@
+package sentinel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchCoalesceWindow is how long Watch waits after the last event for a
+// given path before emitting it, so that a single bulk write (which the
+// kernel typically reports as several IN_MODIFY events) only produces one
+// Event on the channel.
+const watchCoalesceWindow = 250 * time.Millisecond
+
+// Watch supplements Scan with an event-driven mode built on inotify (via
+// fsnotify). It recursively registers watches on every path in WatchFiles,
+// translates kernel events into Events as they arrive, and appends each
+// batch to PriorScans just like Scan does. Watch runs until ctx is
+// cancelled, at which point the returned channel is closed.
+func (s *Sentinel) Watch(ctx context.Context) (<-chan Event, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, wf := range s.WatchFiles {
+		if err := addRecursiveWatch(w, wf); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+
+	evs := make(chan Event)
+	go s.watchLoop(ctx, w, evs)
+	return evs, nil
+}
+
+// addRecursiveWatch registers a watch on path, and, if path is a directory,
+// on every subdirectory beneath it.
+func addRecursiveWatch(w *fsnotify.Watcher, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return w.Add(path)
+	}
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return w.Add(p)
+		}
+		return nil
+	})
+}
+
+// watchLoop translates raw fsnotify events for a single sentinel into
+// Sentinel Events, coalescing bursts of events for the same path within
+// watchCoalesceWindow before recomputing its checksum.
+func (s *Sentinel) watchLoop(ctx context.Context, w *fsnotify.Watcher, evs chan<- Event) {
+	defer w.Close()
+	defer close(evs)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	pending := make(map[string]*time.Timer)
+
+	// stopPending cancels a still-armed debounce timer for path and, if it
+	// hadn't already fired, releases the waitgroup slot reserved for it.
+	// Callers must hold mu.
+	stopPending := func(path string) {
+		if t, ok := pending[path]; ok {
+			if t.Stop() {
+				wg.Done()
+			}
+			delete(pending, path)
+		}
+	}
+
+	flush := func(path string) {
+		defer wg.Done()
+		mu.Lock()
+		delete(pending, path)
+		mu.Unlock()
+
+		fileEvs, err := s.process(path, make(map[string]struct{}))
+		if err != nil {
+			// The path may have been removed or replaced between the event
+			// firing and the debounce window elapsing; nothing to report.
+			return
+		}
+		for _, ev := range fileEvs {
+			select {
+			case evs <- ev:
+				s.recordScan([]Event{ev})
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Cancel every still-armed debounce timer and wait for any that
+			// already fired to finish, so no flush can send on evs after the
+			// deferred close(evs) below runs.
+			mu.Lock()
+			for path := range pending {
+				stopPending(path)
+			}
+			mu.Unlock()
+			wg.Wait()
+			return
+		case _, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// A new subdirectory appeared under a watched root; add
+					// a recursive watch so its contents are covered too.
+					addRecursiveWatch(w, event.Name)
+				}
+			}
+
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				mu.Lock()
+				stopPending(event.Name)
+				mu.Unlock()
+				if ev, ok := s.removeKnownObject(event.Name); ok {
+					select {
+					case evs <- ev:
+						s.recordScan([]Event{ev})
+					case <-ctx.Done():
+						return
+					}
+				}
+				continue
+			}
+
+			mu.Lock()
+			if t, ok := pending[event.Name]; ok {
+				t.Reset(watchCoalesceWindow)
+			} else {
+				path := event.Name
+				wg.Add(1)
+				pending[path] = time.AfterFunc(watchCoalesceWindow, func() { flush(path) })
+			}
+			mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package sentinel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// watchTestTimeout bounds how long a test waits for a single Event,
+// comfortably longer than watchCoalesceWindow so a slow CI box doesn't
+// produce a flaky failure.
+const watchTestTimeout = 2 * time.Second
+
+// waitForEvent reads from evs until it sees one matching file and evtype,
+// or fails the test once watchTestTimeout elapses.
+func waitForEvent(t *testing.T, evs <-chan Event, file, evtype string) Event {
+	t.Helper()
+	deadline := time.After(watchTestTimeout)
+	for {
+		select {
+		case ev, ok := <-evs:
+			if !ok {
+				t.Fatalf("event channel closed waiting for %s on %s", evtype, file)
+			}
+			if ev.File == file && ev.Evtype == evtype {
+				return ev
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for %s on %s", evtype, file)
+		}
+	}
+}
+
+// TestWatchCreateModifyDelete verifies that Watch reports evCreate, evModify,
+// and evDelete for a file created, written to, and removed inside a watched
+// directory.
+func TestWatchCreateModifyDelete(t *testing.T) {
+	dir, err := os.MkdirTemp("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := New(Config{WatchFiles: []string{dir}})
+	if _, err := s.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	evs, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "file1")
+	if _, err := createRandFile(path, 64); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, evs, path, evCreate)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("more data")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	waitForEvent(t, evs, path, evModify)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, evs, path, evDelete)
+
+	cancel()
+	select {
+	case _, ok := <-evs:
+		if ok {
+			t.Fatal("expected event channel to close after ctx cancellation")
+		}
+	case <-time.After(watchTestTimeout):
+		t.Fatal("event channel did not close after ctx cancellation")
+	}
+}
+
+// TestWatchCoalescesBurstWrites verifies that several rapid writes to the
+// same file within watchCoalesceWindow produce a single evModify, not one
+// per write.
+func TestWatchCoalescesBurstWrites(t *testing.T) {
+	dir, err := os.MkdirTemp("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file1")
+	if _, err := createRandFile(path, 64); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(Config{WatchFiles: []string{dir}})
+	if _, err := s.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	evs, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		time.Sleep(watchCoalesceWindow / 10)
+	}
+
+	waitForEvent(t, evs, path, evModify)
+
+	select {
+	case ev, ok := <-evs:
+		if ok {
+			t.Fatal("expected the burst to coalesce into a single event, got a second:", ev)
+		}
+	case <-time.After(watchCoalesceWindow * 2):
+		// no further event arrived, as expected
+	}
+}
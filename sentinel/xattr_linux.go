@@ -0,0 +1,65 @@
+//go:build linux
+
+package sentinel
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// readXattrs reads the extended attributes spid cares about for path:
+// security.capability (Linux file capabilities, the classic way to grant a
+// binary privilege without setuid) and any user.* attribute. Listing every
+// attribute on every scan would be noisy and mostly irrelevant to
+// integrity; these are the ones attackers actually tamper with.
+func readXattrs(path string) (map[string]string, error) {
+	sz, err := unix.Llistxattr(path, nil)
+	if err != nil {
+		if err == unix.ENOTSUP || err == unix.EOPNOTSUPP {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if sz == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, sz)
+	if _, err := unix.Llistxattr(path, buf); err != nil {
+		return nil, err
+	}
+
+	var attrs map[string]string
+	for _, name := range strings.Split(strings.TrimRight(string(buf), "\x00"), "\x00") {
+		if name == "" || !watchedXattr(name) {
+			continue
+		}
+		val, err := lgetxattr(path, name)
+		if err != nil {
+			continue
+		}
+		if attrs == nil {
+			attrs = make(map[string]string)
+		}
+		attrs[name] = val
+	}
+	return attrs, nil
+}
+
+func lgetxattr(path, name string) (string, error) {
+	vsz, err := unix.Lgetxattr(path, name, nil)
+	if err != nil {
+		return "", err
+	}
+	val := make([]byte, vsz)
+	n, err := unix.Lgetxattr(path, name, val)
+	if err != nil {
+		return "", err
+	}
+	return string(val[:n]), nil
+}
+
+func watchedXattr(name string) bool {
+	return name == "security.capability" || strings.HasPrefix(name, "user.")
+}
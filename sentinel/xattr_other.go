@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sentinel
+
+// readXattrs is a no-op on platforms without Linux's extended attribute
+// syscalls; spid still tracks everything else about the path.
+func readXattrs(path string) (map[string]string, error) {
+	return nil, nil
+}